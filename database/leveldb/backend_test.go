@@ -0,0 +1,82 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// testBackends enumerates one instance of every DB implementation so the
+// suite below runs identically against all of them, plus a teardown func to
+// release any resources they hold.
+func testBackends(t *testing.T) (map[string]DB, func()) {
+	fsDir, err := ioutil.TempDir("", "fsdb_backend_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]DB{
+		"memdb":   NewMemDB(""),
+		"fsdb":    NewFSDB(fsDir),
+		"cachedb": NewMemDB("").CacheWrap(),
+	}, func() { os.RemoveAll(fsDir) }
+}
+
+// TestBackendGetSetDeleteHas runs the same get/set/delete/has suite,
+// including the nil-vs-empty-key/value invariant, against every backend.
+func TestBackendGetSetDeleteHas(t *testing.T) {
+	dbs, teardown := testBackends(t)
+	defer teardown()
+
+	for name, db := range dbs {
+		if db.Has([]byte("a")) {
+			t.Fatalf("%s: fresh db should not have key \"a\"", name)
+		}
+		if got := db.Get([]byte("a")); got != nil {
+			t.Fatalf("%s: fresh db Get(\"a\") = %q, want nil", name, got)
+		}
+
+		db.Set([]byte("a"), []byte("1"))
+		if !db.Has([]byte("a")) {
+			t.Fatalf("%s: Has(\"a\") false after Set", name)
+		}
+		if got := string(db.Get([]byte("a"))); got != "1" {
+			t.Fatalf("%s: Get(\"a\") = %q, want \"1\"", name, got)
+		}
+
+		db.Delete([]byte("a"))
+		if db.Has([]byte("a")) {
+			t.Fatalf("%s: Has(\"a\") true after Delete", name)
+		}
+		if got := db.Get([]byte("a")); got != nil {
+			t.Fatalf("%s: Get(\"a\") = %q after Delete, want nil", name, got)
+		}
+	}
+}
+
+// TestBackendNilKeyEqualsEmptyKey enforces that nil and []byte{} are
+// interchangeable for every Get/Set/Delete/Has call, on every backend.
+func TestBackendNilKeyEqualsEmptyKey(t *testing.T) {
+	dbs, teardown := testBackends(t)
+	defer teardown()
+
+	for name, db := range dbs {
+		db.Set(nil, []byte("x"))
+		if !db.Has([]byte{}) {
+			t.Fatalf("%s: Has([]byte{}) false after Set(nil, ...)", name)
+		}
+		if got := string(db.Get([]byte{})); got != "x" {
+			t.Fatalf("%s: Get([]byte{}) = %q, want \"x\"", name, got)
+		}
+
+		db.Delete([]byte{})
+		if db.Has(nil) {
+			t.Fatalf("%s: Has(nil) true after Delete([]byte{})", name)
+		}
+
+		db.Set([]byte{}, nil)
+		if got := db.Get(nil); got == nil || len(got) != 0 {
+			t.Fatalf("%s: Get(nil) = %q, want empty non-nil value", name, got)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// cacheWrapWriteMutex guards cacheWrapVersions so that two CacheDBs wrapping
+// the same parent can't both flush their buffer without either of them
+// noticing the other one raced ahead.
+var (
+	cacheWrapWriteMutex sync.Mutex
+	cacheWrapVersions   = make(map[DB]int64)
+)
+
+type cacheItem struct {
+	value   []byte
+	deleted bool
+}
+
+// CacheDB wraps a parent DB with an in-memory write buffer. Reads fall
+// through to the parent for keys that haven't been touched locally; writes
+// are only recorded in the buffer until Write() flushes them to the parent.
+// This gives callers transaction-like, speculative-execution semantics on
+// top of any backend, including another CacheDB.
+type CacheDB struct {
+	mtx    sync.Mutex
+	cache  map[string]cacheItem
+	parent DB
+
+	// version is the parent's cacheWrapVersions value this CacheDB was
+	// created against. Write() refuses to flush if the parent has since
+	// been written to by another CacheWrap, since that write may have
+	// raced with the reads this CacheDB's buffer was built on.
+	version int64
+}
+
+func NewCacheDB(parent DB) *CacheDB {
+	cacheWrapWriteMutex.Lock()
+	defer cacheWrapWriteMutex.Unlock()
+
+	return &CacheDB{
+		cache:   make(map[string]cacheItem),
+		parent:  parent,
+		version: cacheWrapVersions[parent],
+	}
+}
+
+func (cdb *CacheDB) Get(key []byte) []byte {
+	key = nonNilBytes(key)
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+
+	if item, ok := cdb.cache[string(key)]; ok {
+		if item.deleted {
+			return nil
+		}
+		return item.value
+	}
+	return cdb.parent.Get(key)
+}
+
+func (cdb *CacheDB) Has(key []byte) bool {
+	key = nonNilBytes(key)
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+
+	if item, ok := cdb.cache[string(key)]; ok {
+		return !item.deleted
+	}
+	return cdb.parent.Has(key)
+}
+
+func (cdb *CacheDB) Set(key, value []byte) {
+	key = nonNilBytes(key)
+	value = nonNilBytes(value)
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+	cdb.cache[string(key)] = cacheItem{value: value}
+}
+
+func (cdb *CacheDB) SetSync(key, value []byte) {
+	cdb.Set(key, value)
+}
+
+func (cdb *CacheDB) Delete(key []byte) {
+	key = nonNilBytes(key)
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+	cdb.cache[string(key)] = cacheItem{deleted: true}
+}
+
+func (cdb *CacheDB) DeleteSync(key []byte) {
+	cdb.Delete(key)
+}
+
+func (cdb *CacheDB) Close() {
+	// Close is a noop: a CacheDB holds no resources of its own, only a
+	// reference to its parent, which owns its own lifecycle.
+}
+
+func (cdb *CacheDB) Print() {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+	for key, item := range cdb.cache {
+		if item.deleted {
+			continue
+		}
+		fmt.Printf("[%X]:\t[%X]\n", []byte(key), item.value)
+	}
+}
+
+func (cdb *CacheDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	stats["database.type"] = "cacheDB"
+	return stats
+}
+
+// CacheWrap wraps cdb in another CacheDB, letting callers nest speculative
+// layers of buffering on top of each other.
+func (cdb *CacheDB) CacheWrap() DB {
+	return NewCacheDB(cdb)
+}
+
+// Write flushes the buffered Set/Delete calls to the parent DB under
+// cacheWrapWriteMutex, then clears the buffer so the CacheDB can keep being
+// used. It panics if the parent was written to by another CacheWrap since
+// this one was created, since this CacheDB's reads may be stale.
+func (cdb *CacheDB) Write() {
+	cacheWrapWriteMutex.Lock()
+	defer cacheWrapWriteMutex.Unlock()
+
+	if cacheWrapVersions[cdb.parent] != cdb.version {
+		panic("CacheDB.Write: parent was modified by another CacheWrap since this one was created")
+	}
+
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+
+	for key, item := range cdb.cache {
+		if item.deleted {
+			cdb.parent.Delete([]byte(key))
+		} else {
+			cdb.parent.Set([]byte(key), item.value)
+		}
+	}
+	cdb.cache = make(map[string]cacheItem)
+
+	cdb.version++
+	cacheWrapVersions[cdb.parent] = cdb.version
+}
+
+func (cdb *CacheDB) NewBatch() Batch {
+	return &cacheDBBatch{cdb, nil}
+}
+
+func (cdb *CacheDB) Iterator(start, end []byte) Iterator {
+	return cdb.iterator(start, end, false)
+}
+
+func (cdb *CacheDB) ReverseIterator(start, end []byte) Iterator {
+	return cdb.iterator(start, end, true)
+}
+
+// iterator merges the cache's keys in [start, end) with the parent's, under
+// cdb.mtx so the two views line up, preferring the cache's view of a key
+// (including tombstones for deleted keys) wherever a key exists on both
+// sides.
+func (cdb *CacheDB) iterator(start, end []byte, reverse bool) Iterator {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+
+	inRange := func(key string) bool {
+		return (start == nil || key >= string(start)) && (end == nil || key < string(end))
+	}
+
+	seen := make(map[string]bool, len(cdb.cache))
+	keys := make([]string, 0, len(cdb.cache))
+	for key, item := range cdb.cache {
+		seen[key] = true
+		if item.deleted {
+			continue
+		}
+		if inRange(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	parent := cdb.parent.Iterator(start, end)
+	defer parent.Close()
+	for ; parent.Valid(); parent.Next() {
+		key := string(parent.Key())
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	if reverse {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	return newCacheDBIterator(cdb, keys)
+}
+
+//--------------------------------------------------------------------------------
+
+type cacheDBBatch struct {
+	cdb *CacheDB
+	ops []operation
+}
+
+func (b *cacheDBBatch) Set(key, value []byte) {
+	b.ops = append(b.ops, operation{opTypeSet, nonNilBytes(key), nonNilBytes(value)})
+}
+
+func (b *cacheDBBatch) Delete(key []byte) {
+	b.ops = append(b.ops, operation{opTypeDelete, nonNilBytes(key), nil})
+}
+
+func (b *cacheDBBatch) Write() {
+	for _, op := range b.ops {
+		if op.opType == opTypeSet {
+			b.cdb.Set(op.key, op.value)
+		} else if op.opType == opTypeDelete {
+			b.cdb.Delete(op.key)
+		}
+	}
+}
+
+//--------------------------------------------------------------------------------
+
+// cacheDBIterator walks a pre-sorted, already-merged snapshot of keys,
+// resolving each value through the CacheDB so a key still shows the cache's
+// value even if it was overwritten after the iterator was built. Key()/
+// Value() return fresh copies so callers can't mutate CacheDB's internals.
+type cacheDBIterator struct {
+	cdb  *CacheDB
+	keys []string
+	last int
+}
+
+func newCacheDBIterator(cdb *CacheDB, keys []string) *cacheDBIterator {
+	return &cacheDBIterator{cdb: cdb, keys: keys, last: 0}
+}
+
+func (it *cacheDBIterator) Valid() bool {
+	return it.last < len(it.keys)
+}
+
+func (it *cacheDBIterator) Next() {
+	it.last++
+}
+
+func (it *cacheDBIterator) Key() []byte {
+	key := make([]byte, len(it.keys[it.last]))
+	copy(key, it.keys[it.last])
+	return key
+}
+
+func (it *cacheDBIterator) Value() []byte {
+	value := it.cdb.Get(it.Key())
+	if value == nil {
+		return nil
+	}
+	cp := make([]byte, len(value))
+	copy(cp, value)
+	return cp
+}
+
+func (it *cacheDBIterator) Close() {
+	it.cdb = nil
+	it.keys = nil
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestCacheDBGetSetDelete(t *testing.T) {
+	parent := NewMemDB("")
+	parent.Set([]byte("a"), []byte("1"))
+
+	cache := parent.CacheWrap()
+	if got := string(cache.Get([]byte("a"))); got != "1" {
+		t.Fatalf("expected cache to read through to parent, got %q", got)
+	}
+
+	cache.Set([]byte("a"), []byte("2"))
+	cache.Set([]byte("b"), []byte("3"))
+	if got := string(parent.Get([]byte("a"))); got != "1" {
+		t.Fatalf("parent should be untouched before Write(), got %q", got)
+	}
+	if got := string(cache.Get([]byte("a"))); got != "2" {
+		t.Fatalf("cache should see its own uncommitted write, got %q", got)
+	}
+
+	cache.Delete([]byte("a"))
+	if got := cache.Get([]byte("a")); got != nil {
+		t.Fatalf("expected deleted key to read nil from cache, got %q", got)
+	}
+
+	cache.(*CacheDB).Write()
+	if got := parent.Get([]byte("a")); got != nil {
+		t.Fatalf("expected delete to flush through to parent, got %q", got)
+	}
+	if got := string(parent.Get([]byte("b"))); got != "3" {
+		t.Fatalf("expected set to flush through to parent, got %q", got)
+	}
+}
+
+func TestCacheDBNestedCacheWrap(t *testing.T) {
+	parent := NewMemDB("")
+	outer := parent.CacheWrap()
+	outer.Set([]byte("a"), []byte("1"))
+
+	inner := outer.CacheWrap()
+	if got := string(inner.Get([]byte("a"))); got != "1" {
+		t.Fatalf("expected nested CacheDB to read through to outer, got %q", got)
+	}
+
+	inner.Set([]byte("a"), []byte("2"))
+	inner.(*CacheDB).Write()
+	if got := string(outer.Get([]byte("a"))); got != "2" {
+		t.Fatalf("expected inner Write() to flush into outer, got %q", got)
+	}
+	if got := parent.Get([]byte("a")); got != nil {
+		t.Fatalf("outer Write() was never called, parent should be untouched, got %q", got)
+	}
+
+	outer.(*CacheDB).Write()
+	if got := string(parent.Get([]byte("a"))); got != "2" {
+		t.Fatalf("expected outer Write() to flush into parent, got %q", got)
+	}
+}
+
+func TestCacheDBConcurrentWriteDetection(t *testing.T) {
+	parent := NewMemDB("")
+	a := parent.CacheWrap().(*CacheDB)
+	b := parent.CacheWrap().(*CacheDB)
+
+	a.Set([]byte("x"), []byte("1"))
+	a.Write()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Write() on a stale CacheDB to panic")
+		}
+	}()
+	b.Set([]byte("y"), []byte("2"))
+	b.Write()
+}
+
+func TestCacheDBIteratorMergesCacheAndParent(t *testing.T) {
+	parent := NewMemDB("")
+	parent.Set([]byte("a"), []byte("1"))
+	parent.Set([]byte("b"), []byte("2"))
+	parent.Set([]byte("c"), []byte("3"))
+
+	cache := parent.CacheWrap()
+	cache.Delete([]byte("b"))
+	cache.Set([]byte("d"), []byte("4"))
+
+	it := cache.Iterator(BeginningKey(), EndingKey())
+	var got []string
+	for ; it.Valid(); it.Next() {
+		got = append(got, string(it.Key())+"="+string(it.Value()))
+	}
+	it.Close()
+
+	want := []string{"a=1", "c=3", "d=4"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
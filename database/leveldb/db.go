@@ -0,0 +1,99 @@
+package db
+
+import "fmt"
+
+type DBBackendType string
+
+const (
+	LevelDBBackendStr DBBackendType = "leveldb"
+	MemDBBackendStr   DBBackendType = "memdb"
+	FSDBBackendStr    DBBackendType = "fsdb"
+)
+
+type dbCreator func(name string, dir string) (DB, error)
+
+var backends = map[DBBackendType]dbCreator{}
+
+func registerDBCreator(backend DBBackendType, creator dbCreator, force bool) {
+	_, ok := backends[backend]
+	if !force && ok {
+		return
+	}
+	backends[backend] = creator
+}
+
+// NewDB creates a new database of the given backend type, registered via
+// registerDBCreator in each backend's init().
+func NewDB(name string, backend DBBackendType, dir string) DB {
+	dbCreator, ok := backends[backend]
+	if !ok {
+		keys := make([]string, 0, len(backends))
+		for k := range backends {
+			keys = append(keys, string(k))
+		}
+		panic(fmt.Sprintf("Unknown db_backend %s, expected one of %v", backend, keys))
+	}
+
+	db, err := dbCreator(name, dir)
+	if err != nil {
+		panic(fmt.Sprintf("Error initializing DB: %v", err))
+	}
+	return db
+}
+
+// DB is the interface implemented by every backend in this package. It is
+// intentionally close to a plain key/value map so that backends can be
+// swapped without touching calling code.
+type DB interface {
+	Get([]byte) []byte
+	Has(key []byte) bool
+	Set([]byte, []byte)
+	SetSync([]byte, []byte)
+	Delete([]byte)
+	DeleteSync([]byte)
+	Close()
+	NewBatch() Batch
+
+	// Iterator and ReverseIterator return the key/value pairs in
+	// [start, end) — use BeginningKey()/EndingKey() for an unbounded side.
+	Iterator(start, end []byte) Iterator
+	ReverseIterator(start, end []byte) Iterator
+
+	Print()
+	Stats() map[string]string
+
+	// CacheWrap returns a CacheDB that buffers writes against this DB in
+	// memory until Write() is called.
+	CacheWrap() DB
+}
+
+// Batch groups a sequence of Set/Delete operations so they can be applied
+// to a DB together.
+type Batch interface {
+	Set(key, value []byte)
+	Delete(key []byte)
+	Write()
+}
+
+// Iterator walks a DB's key range in order, LevelDB-style: check Valid(),
+// read Key()/Value(), then Next(), until Valid() turns false. Close must be
+// called once the iterator is no longer needed.
+type Iterator interface {
+	Valid() bool
+	Next()
+	Key() []byte
+	Value() []byte
+	Close()
+}
+
+// BeginningKey is the sentinel passed as an Iterator/ReverseIterator start
+// to mean "no lower bound".
+func BeginningKey() []byte {
+	return nil
+}
+
+// EndingKey is the sentinel passed as an Iterator/ReverseIterator end to
+// mean "no upper bound".
+func EndingKey() []byte {
+	return nil
+}
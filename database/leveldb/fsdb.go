@@ -0,0 +1,261 @@
+package db
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+func init() {
+	registerDBCreator(FSDBBackendStr, func(name string, dir string) (DB, error) {
+		return NewFSDB(filepath.Join(dir, name+".db")), nil
+	}, false)
+}
+
+// FSDB is a zero-dependency DB backend that stores every key/value pair as
+// its own file under dir, named fsKeyPrefix followed by the hex encoding of
+// the key. It implements the same DB interface as MemDB so it can stand in
+// for it via NewDB (MemDB's Dump/Load/SaveToFile/AutoFlush have no FSDB
+// counterpart), and is useful for debugging or small, crash-visible datasets
+// where LevelDB is overkill.
+type FSDB struct {
+	mtx sync.RWMutex
+	dir string
+}
+
+func NewFSDB(dir string) *FSDB {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		panic(fmt.Sprintf("Error creating FSDB directory %s: %v", dir, err))
+	}
+	return &FSDB{dir: dir}
+}
+
+// fsKeyPrefix distinguishes a key's file from db.dir itself, since an empty
+// key would otherwise hex-encode to the empty string.
+const fsKeyPrefix = "k-"
+
+func (db *FSDB) keyPath(key []byte) string {
+	return filepath.Join(db.dir, fsKeyPrefix+hex.EncodeToString(key))
+}
+
+func (db *FSDB) Get(key []byte) []byte {
+	key = nonNilBytes(key)
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+	return db.get(key)
+}
+
+func (db *FSDB) get(key []byte) []byte {
+	value, err := ioutil.ReadFile(db.keyPath(key))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		panic(fmt.Sprintf("Error reading FSDB value for key %X: %v", key, err))
+	}
+	return value
+}
+
+func (db *FSDB) Has(key []byte) bool {
+	key = nonNilBytes(key)
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+	_, err := os.Stat(db.keyPath(key))
+	return err == nil
+}
+
+func (db *FSDB) Set(key, value []byte) {
+	key, value = nonNilBytes(key), nonNilBytes(value)
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+	db.set(key, value, false)
+}
+
+func (db *FSDB) SetSync(key, value []byte) {
+	key, value = nonNilBytes(key), nonNilBytes(value)
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+	db.set(key, value, true)
+}
+
+func (db *FSDB) set(key, value []byte, sync bool) {
+	f, err := os.OpenFile(db.keyPath(key), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing FSDB value for key %X: %v", key, err))
+	}
+	defer f.Close()
+	if _, err := f.Write(value); err != nil {
+		panic(fmt.Sprintf("Error writing FSDB value for key %X: %v", key, err))
+	}
+	if sync {
+		if err := f.Sync(); err != nil {
+			panic(fmt.Sprintf("Error syncing FSDB value for key %X: %v", key, err))
+		}
+	}
+}
+
+func (db *FSDB) Delete(key []byte) {
+	key = nonNilBytes(key)
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+	db.delete(key)
+}
+
+func (db *FSDB) DeleteSync(key []byte) {
+	db.Delete(key)
+}
+
+func (db *FSDB) delete(key []byte) {
+	if err := os.Remove(db.keyPath(key)); err != nil && !os.IsNotExist(err) {
+		panic(fmt.Sprintf("Error deleting FSDB value for key %X: %v", key, err))
+	}
+}
+
+func (db *FSDB) Close() {
+	// Close is a noop: FSDB has no open handles outside of the lifetime of
+	// an individual Get/Set/Delete call.
+}
+
+func (db *FSDB) Print() {
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+	for _, key := range db.sortedKeys(nil, nil, false) {
+		fmt.Printf("[%X]:\t[%X]\n", key, db.get(key))
+	}
+}
+
+func (db *FSDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	stats["database.type"] = "fsDB"
+	stats["database.dir"] = db.dir
+	return stats
+}
+
+func (db *FSDB) CacheWrap() DB {
+	return NewCacheDB(db)
+}
+
+// sortedKeys lists db.dir, decodes each entry name back into a raw key,
+// filters out anything that doesn't decode (e.g. stray non-FSDB files), and
+// returns the keys in [start, end), sorted ascending, or descending if
+// reverse is set. A nil start/end means unbounded on that side.
+func (db *FSDB) sortedKeys(start, end []byte, reverse bool) [][]byte {
+	entries, err := ioutil.ReadDir(db.dir)
+	if err != nil {
+		panic(fmt.Sprintf("Error listing FSDB directory %s: %v", db.dir, err))
+	}
+
+	keys := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), fsKeyPrefix) {
+			continue
+		}
+		key, err := hex.DecodeString(strings.TrimPrefix(entry.Name(), fsKeyPrefix))
+		if err != nil {
+			continue
+		}
+		if start != nil && bytes.Compare(key, start) < 0 {
+			continue
+		}
+		if end != nil && bytes.Compare(key, end) >= 0 {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+	if reverse {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+	return keys
+}
+
+func (db *FSDB) Iterator(start, end []byte) Iterator {
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+	return newFsDBIterator(db, db.sortedKeys(start, end, false))
+}
+
+func (db *FSDB) ReverseIterator(start, end []byte) Iterator {
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+	return newFsDBIterator(db, db.sortedKeys(start, end, true))
+}
+
+func (db *FSDB) NewBatch() Batch {
+	return &fsDBBatch{db, nil}
+}
+
+//--------------------------------------------------------------------------------
+
+type fsDBBatch struct {
+	db  *FSDB
+	ops []operation
+}
+
+func (b *fsDBBatch) Set(key, value []byte) {
+	b.ops = append(b.ops, operation{opTypeSet, nonNilBytes(key), nonNilBytes(value)})
+}
+
+func (b *fsDBBatch) Delete(key []byte) {
+	b.ops = append(b.ops, operation{opTypeDelete, nonNilBytes(key), nil})
+}
+
+func (b *fsDBBatch) Write() {
+	b.db.mtx.Lock()
+	defer b.db.mtx.Unlock()
+
+	for _, op := range b.ops {
+		if op.opType == opTypeSet {
+			b.db.set(op.key, op.value, false)
+		} else if op.opType == opTypeDelete {
+			b.db.delete(op.key)
+		}
+	}
+}
+
+//--------------------------------------------------------------------------------
+
+type fsDBIterator struct {
+	db   *FSDB
+	keys [][]byte
+	last int
+}
+
+func newFsDBIterator(db *FSDB, keys [][]byte) *fsDBIterator {
+	return &fsDBIterator{db: db, keys: keys, last: 0}
+}
+
+func (it *fsDBIterator) Valid() bool {
+	return it.last < len(it.keys)
+}
+
+func (it *fsDBIterator) Next() {
+	it.last++
+}
+
+func (it *fsDBIterator) Key() []byte {
+	key := make([]byte, len(it.keys[it.last]))
+	copy(key, it.keys[it.last])
+	return key
+}
+
+func (it *fsDBIterator) Value() []byte {
+	// ioutil.ReadFile always returns a freshly allocated buffer, so this
+	// needs no extra defensive copy.
+	return it.db.Get(it.Key())
+}
+
+func (it *fsDBIterator) Close() {
+	it.db = nil
+	it.keys = nil
+}
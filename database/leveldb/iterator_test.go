@@ -0,0 +1,60 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// testDBs returns one instance of every registered backend, plus a
+// teardown func to release any resources they hold.
+func testDBs(t *testing.T) (map[string]DB, func()) {
+	fsDir, err := ioutil.TempDir("", "fsdb_iterator_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbs := map[string]DB{
+		"memdb": NewMemDB(""),
+		"fsdb":  NewFSDB(fsDir),
+	}
+	return dbs, func() { os.RemoveAll(fsDir) }
+}
+
+func collect(it Iterator) []string {
+	var got []string
+	for ; it.Valid(); it.Next() {
+		got = append(got, string(it.Key()))
+	}
+	it.Close()
+	return got
+}
+
+func assertKeys(t *testing.T, name string, got, want []string) {
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %v, want %v", name, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s: got %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestIteratorRanges(t *testing.T) {
+	dbs, teardown := testDBs(t)
+	defer teardown()
+
+	for name, db := range dbs {
+		db.Set([]byte("a"), []byte("1"))
+		db.Set([]byte("b"), []byte("2"))
+		db.Set([]byte("c"), []byte("3"))
+
+		assertKeys(t, name+"/unbounded", collect(db.Iterator(BeginningKey(), EndingKey())), []string{"a", "b", "c"})
+		assertKeys(t, name+"/unbounded-start", collect(db.Iterator(BeginningKey(), []byte("c"))), []string{"a", "b"})
+		assertKeys(t, name+"/unbounded-end", collect(db.Iterator([]byte("b"), EndingKey())), []string{"b", "c"})
+		assertKeys(t, name+"/bounded", collect(db.Iterator([]byte("b"), []byte("c"))), []string{"b"})
+		assertKeys(t, name+"/empty-range", collect(db.Iterator([]byte("x"), []byte("y"))), nil)
+		assertKeys(t, name+"/reverse", collect(db.ReverseIterator(BeginningKey(), EndingKey())), []string{"c", "b", "a"})
+	}
+}
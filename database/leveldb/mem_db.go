@@ -2,56 +2,189 @@ package db
 
 import (
 	"bytes"
+	"encoding/gob"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"sort"
-	"strings"
 	"sync"
+	"time"
 )
 
 func init() {
 	registerDBCreator(MemDBBackendStr, func(name string, dir string) (DB, error) {
-		return NewMemDB(), nil
+		if dir == "" {
+			return NewMemDB(""), nil
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		return NewMemDB(filepath.Join(dir, name+".db")), nil
 	}, false)
 }
 
+// MemDB is a map-backed DB. Passing a non-empty path makes it a lightweight
+// persistent option for small datasets and test fixtures: the dump at path
+// is loaded on construction if present, and SaveToFile/AutoFlush write it
+// back out, without pulling in a full KV engine.
 type MemDB struct {
-	mtx sync.Mutex
-	db  map[string][]byte
+	mtx  sync.Mutex
+	db   map[string][]byte
+	path string
 }
 
-func NewMemDB() *MemDB {
-	database := &MemDB{db: make(map[string][]byte)}
+// NewMemDB builds an empty MemDB. If path is non-empty and a dump file
+// already exists there (as written by SaveToFile or a prior AutoFlush), it
+// is loaded automatically; a missing or corrupted dump is treated as an
+// empty starting point rather than a construction error.
+func NewMemDB(path string) *MemDB {
+	database := &MemDB{db: make(map[string][]byte), path: path}
+	if path == "" {
+		return database
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return database
+	}
+	defer f.Close()
+
+	if err := database.Load(f); err != nil {
+		database.db = make(map[string][]byte)
+	}
 	return database
 }
 
+// NewMemDBFromFile loads a MemDB from an existing gob dump at path,
+// returning an error if the file is missing or can't be decoded.
+func NewMemDBFromFile(path string) (*MemDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	database := &MemDB{db: make(map[string][]byte), path: path}
+	if err := database.Load(f); err != nil {
+		return nil, err
+	}
+	return database, nil
+}
+
+// Dump gob-encodes the current contents of db to w. The map is copied
+// under db.mtx and then encoded without holding it, so Dump doesn't block
+// concurrent Get/Set/Delete calls for the duration of the write to w.
+func (db *MemDB) Dump(w io.Writer) error {
+	db.mtx.Lock()
+	snapshot := make(map[string][]byte, len(db.db))
+	for k, v := range db.db {
+		snapshot[k] = v
+	}
+	db.mtx.Unlock()
+
+	return gob.NewEncoder(w).Encode(snapshot)
+}
+
+// Load replaces db's contents with the gob-encoded map read from r. On
+// error, db is left untouched.
+func (db *MemDB) Load(r io.Reader) error {
+	loaded := make(map[string][]byte)
+	if err := gob.NewDecoder(r).Decode(&loaded); err != nil {
+		return err
+	}
+
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+	db.db = loaded
+	return nil
+}
+
+// SaveToFile dumps db to a temp file alongside path, fsyncs it, and renames
+// it into place, so a reader opening path never observes a partial write.
+func (db *MemDB) SaveToFile(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Dump(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// AutoFlush periodically calls SaveToFile(db.path) every interval until the
+// returned stop func is called. It panics if db has no path configured —
+// construct it via NewMemDB(path) or NewMemDBFromFile first.
+func (db *MemDB) AutoFlush(interval time.Duration) (stop func()) {
+	if db.path == "" {
+		panic("MemDB.AutoFlush: no path configured, use NewMemDB(path) or NewMemDBFromFile")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := db.SaveToFile(db.path); err != nil {
+					panic(fmt.Sprintf("MemDB.AutoFlush: error saving to %s: %v", db.path, err))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
 func (db *MemDB) Get(key []byte) []byte {
+	key = nonNilBytes(key)
 	db.mtx.Lock()
 	defer db.mtx.Unlock()
 	return db.db[string(key)]
 }
 
-func (db *MemDB) Set(key []byte, value []byte) {
+func (db *MemDB) Has(key []byte) bool {
+	key = nonNilBytes(key)
 	db.mtx.Lock()
 	defer db.mtx.Unlock()
-	db.db[string(key)] = value
+	_, ok := db.db[string(key)]
+	return ok
 }
 
-func (db *MemDB) SetSync(key []byte, value []byte) {
+func (db *MemDB) Set(key []byte, value []byte) {
+	key = nonNilBytes(key)
+	value = nonNilBytes(value)
 	db.mtx.Lock()
 	defer db.mtx.Unlock()
 	db.db[string(key)] = value
 }
 
+func (db *MemDB) SetSync(key []byte, value []byte) {
+	db.Set(key, value)
+}
+
 func (db *MemDB) Delete(key []byte) {
+	key = nonNilBytes(key)
 	db.mtx.Lock()
 	defer db.mtx.Unlock()
 	delete(db.db, string(key))
 }
 
 func (db *MemDB) DeleteSync(key []byte) {
-	db.mtx.Lock()
-	defer db.mtx.Unlock()
-	delete(db.db, string(key))
+	db.Delete(key)
 }
 
 func (db *MemDB) Close() {
@@ -76,106 +209,80 @@ func (db *MemDB) Stats() map[string]string {
 	return stats
 }
 
+// CacheWrap returns a CacheDB that buffers writes in memory until Write()
+// is called, leaving db untouched until then.
+func (db *MemDB) CacheWrap() DB {
+	return NewCacheDB(db)
+}
+
+// memDBIterator walks a snapshot of sorted keys taken when it was created.
+// Key()/Value() always return freshly copied slices so a caller mutating
+// them can't corrupt MemDB's internal map.
 type memDBIterator struct {
 	last int
 	keys []string
-	db   DB
-
-	start []byte
+	db   *MemDB
 }
 
-func newMemDBIterator() *memDBIterator {
-	return &memDBIterator{}
+func newMemDBIterator(db *MemDB, keys []string) *memDBIterator {
+	return &memDBIterator{db: db, keys: keys, last: 0}
 }
 
-// Keys is expected to be in reverse order for reverse iterators.
-func newMemDBIteratorWithArgs(db DB, keys []string, start []byte) *memDBIterator {
-	itr := &memDBIterator{
-		db:    db,
-		keys:  keys,
-		start: start,
-		last:  -1,
-	}
-	if start != nil {
-		itr.Seek(start)
-	}
-	return itr
+func (it *memDBIterator) Valid() bool {
+	return it.last < len(it.keys)
 }
 
-func (it *memDBIterator) Next() bool {
-	if it.last >= len(it.keys)-1 {
-		return false
-	}
+func (it *memDBIterator) Next() {
 	it.last++
-	return true
 }
 
 func (it *memDBIterator) Key() []byte {
-	return []byte(it.keys[it.last])
+	key := make([]byte, len(it.keys[it.last]))
+	copy(key, it.keys[it.last])
+	return key
 }
 
 func (it *memDBIterator) Value() []byte {
-	return it.db.Get(it.Key())
-}
-
-func (it *memDBIterator) Seek(point []byte) bool {
-	for i, key := range it.keys {
-		if key >= string(point) {
-			it.last = i
-			return true
-		}
+	value := it.db.Get(it.Key())
+	if value == nil {
+		return nil
 	}
-	return false
+	cp := make([]byte, len(value))
+	copy(cp, value)
+	return cp
 }
 
-func (it *memDBIterator) Release() {
+func (it *memDBIterator) Close() {
 	it.db = nil
 	it.keys = nil
 }
 
-func (it *memDBIterator) Error() error {
-	return nil
-}
-
-func (db *MemDB) Iterator() Iterator {
-	return db.IteratorPrefix([]byte{})
-}
-
-func (db *MemDB) IteratorPrefix(prefix []byte) Iterator {
-	it := newMemDBIterator()
-	it.db = db
-	it.last = -1
-
+func (db *MemDB) Iterator(start, end []byte) Iterator {
 	db.mtx.Lock()
 	defer db.mtx.Unlock()
-
-	// unfortunately we need a copy of all of the keys
-	for key, _ := range db.db {
-		if strings.HasPrefix(key, string(prefix)) {
-			it.keys = append(it.keys, key)
-		}
-	}
-	// and we need to sort them
-	sort.Strings(it.keys)
-	return it
+	return newMemDBIterator(db, db.getSortedKeys(start, end, false))
 }
 
-func (db *MemDB) IteratorPrefixWithStart(Prefix, start []byte, isReverse bool) Iterator {
+func (db *MemDB) ReverseIterator(start, end []byte) Iterator {
 	db.mtx.Lock()
 	defer db.mtx.Unlock()
-
-	keys := db.getSortedKeys(start, isReverse)
-	return newMemDBIteratorWithArgs(db, keys, start)
+	return newMemDBIterator(db, db.getSortedKeys(start, end, true))
 }
 
 func (db *MemDB) NewBatch() Batch {
 	return &memDBBatch{db, nil}
 }
 
-func (db *MemDB) getSortedKeys(start []byte, reverse bool) []string {
+// getSortedKeys returns the keys in [start, end), sorted ascending, or
+// descending if reverse is set. A nil start/end means unbounded on that
+// side — see BeginningKey()/EndingKey().
+func (db *MemDB) getSortedKeys(start, end []byte, reverse bool) []string {
 	keys := []string{}
 	for key := range db.db {
-		if bytes.Compare([]byte(key), start) < 0 {
+		if start != nil && bytes.Compare([]byte(key), start) < 0 {
+			continue
+		}
+		if end != nil && bytes.Compare([]byte(key), end) >= 0 {
 			continue
 		}
 		keys = append(keys, key)
@@ -213,11 +320,11 @@ type operation struct {
 }
 
 func (mBatch *memDBBatch) Set(key, value []byte) {
-	mBatch.ops = append(mBatch.ops, operation{opTypeSet, key, value})
+	mBatch.ops = append(mBatch.ops, operation{opTypeSet, nonNilBytes(key), nonNilBytes(value)})
 }
 
 func (mBatch *memDBBatch) Delete(key []byte) {
-	mBatch.ops = append(mBatch.ops, operation{opTypeDelete, key, nil})
+	mBatch.ops = append(mBatch.ops, operation{opTypeDelete, nonNilBytes(key), nil})
 }
 
 func (mBatch *memDBBatch) Write() {
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestNewDBMemDBWithoutDir covers the common db.NewDB(name, MemDBBackendStr,
+// "") idiom for an ephemeral, non-persistent MemDB — it must not try to
+// mkdir an empty directory.
+func TestNewDBMemDBWithoutDir(t *testing.T) {
+	database := NewDB("test", MemDBBackendStr, "")
+	database.Set([]byte("a"), []byte("1"))
+	if got := string(database.Get([]byte("a"))); got != "1" {
+		t.Fatalf("database.Get(\"a\") = %q, want \"1\"", got)
+	}
+}
+
+func TestMemDBSaveLoadRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mem_db_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dump")
+
+	db := NewMemDB("")
+	db.Set([]byte("a"), []byte("1"))
+	db.Set([]byte("b"), []byte("2"))
+	if err := db.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile: %v", err)
+	}
+
+	loaded := NewMemDB(path)
+	if got := string(loaded.Get([]byte("a"))); got != "1" {
+		t.Fatalf("loaded.Get(\"a\") = %q, want \"1\"", got)
+	}
+	if got := string(loaded.Get([]byte("b"))); got != "2" {
+		t.Fatalf("loaded.Get(\"b\") = %q, want \"2\"", got)
+	}
+
+	fromFile, err := NewMemDBFromFile(path)
+	if err != nil {
+		t.Fatalf("NewMemDBFromFile: %v", err)
+	}
+	if got := string(fromFile.Get([]byte("a"))); got != "1" {
+		t.Fatalf("fromFile.Get(\"a\") = %q, want \"1\"", got)
+	}
+}
+
+func TestMemDBLoadCorruptedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mem_db_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dump")
+
+	if err := ioutil.WriteFile(path, []byte("not a gob stream"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db := NewMemDB(path)
+	if got := db.Get([]byte("a")); got != nil {
+		t.Fatalf("expected empty db after failing to load a corrupted dump, got %q", got)
+	}
+
+	if _, err := NewMemDBFromFile(path); err == nil {
+		t.Fatal("expected NewMemDBFromFile to report an error for a corrupted dump")
+	}
+}
+
+func TestMemDBConcurrentSetDuringDump(t *testing.T) {
+	db := NewMemDB("")
+	for i := 0; i < 100; i++ {
+		db.Set([]byte{byte(i)}, []byte("x"))
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			db.Set([]byte{byte(i)}, []byte("y"))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			db.Dump(ioutil.Discard)
+		}
+	}()
+	wg.Wait()
+}
+
+func TestMemDBAutoFlush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mem_db_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dump")
+
+	db := NewMemDB(path)
+	db.Set([]byte("a"), []byte("1"))
+
+	stop := db.AutoFlush(10 * time.Millisecond)
+	defer stop()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if data, err := ioutil.ReadFile(path); err == nil && len(data) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("AutoFlush never wrote the dump file")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	loaded := NewMemDB(path)
+	if got := string(loaded.Get([]byte("a"))); got != "1" {
+		t.Fatalf("loaded.Get(\"a\") = %q, want \"1\"", got)
+	}
+}
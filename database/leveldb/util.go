@@ -0,0 +1,10 @@
+package db
+
+// nonNilBytes returns bs if it is non-nil, or an empty byte slice otherwise,
+// so that every backend treats a nil key/value identically to []byte{}.
+func nonNilBytes(bs []byte) []byte {
+	if bs == nil {
+		return []byte{}
+	}
+	return bs
+}